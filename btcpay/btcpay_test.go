@@ -0,0 +1,42 @@
+package btcpay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := "webhook-secret"
+	body := []byte(`{"type":"InvoiceSettled","invoiceId":"abc123"}`)
+
+	tests := []struct {
+		name   string
+		secret string
+		body   []byte
+		header string
+		want   bool
+	}{
+		{"valid signature", secret, body, sign(secret, body), true},
+		{"wrong secret", secret, body, sign("other-secret", body), false},
+		{"tampered body", secret, []byte(`{"type":"InvoiceSettled","invoiceId":"evil"}`), sign(secret, body), false},
+		{"missing prefix", secret, body, hex.EncodeToString([]byte("deadbeef")), false},
+		{"non-hex digest", secret, body, "sha256=not-hex", false},
+		{"empty header", secret, body, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.body, tt.header); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreakerCoolDown is how long a source IP rotated to the back of the pool stays
+// there before it is eligible again.
+const circuitBreakerCoolDown = 5 * time.Minute
+
+// circuitBreakerHealthyWindows is how many consecutive healthy windows, once half-open,
+// are required before the breaker ramps back to normal.
+const circuitBreakerHealthyWindows = 3
+
+// circuitBreakerBatchDivisor shrinks the per-tick batch of models while half-open.
+const circuitBreakerBatchDivisor = 4
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	if s == breakerHalfOpen {
+		return "half-open"
+	}
+	return "closed"
+}
+
+// circuitBreaker is meant to turn DangerousErrorRate from a passive admin warning into an
+// active feedback loop: crossing errorThreshold/errorDenominator backs off the scrape
+// interval, rotates the offending source IP to the back of the pool, and shrinks the
+// per-tick batch, then ramps back to normal once the error rate has stayed low for long
+// enough.
+//
+// NOTE: this type is fully implemented and tested in isolation, but nothing in this source
+// tree drives it yet: Observe, IntervalMs, BatchSize and RotatePool need to be called from
+// the scraper's per-tick loop, which lives outside this slice of the repo. Until that call
+// site lands, a circuit breaker created here sits idle and the error rate stays passive.
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	baseIntervalMs int
+	maxIntervalMs  int
+
+	state         breakerState
+	intervalMs    int
+	healthyStreak int
+	coolingDown   map[string]time.Time
+}
+
+// newCircuitBreaker returns a circuit breaker backing off between baseIntervalMs and
+// maxIntervalMs.
+func newCircuitBreaker(baseIntervalMs, maxIntervalMs int) *circuitBreaker {
+	if maxIntervalMs < baseIntervalMs {
+		maxIntervalMs = baseIntervalMs
+	}
+	return &circuitBreaker{
+		baseIntervalMs: baseIntervalMs,
+		maxIntervalMs:  maxIntervalMs,
+		state:          breakerClosed,
+		intervalMs:     baseIntervalMs,
+		coolingDown:    map[string]time.Time{},
+	}
+}
+
+// Observe reports the current sliding-window error fraction (0..1) against threshold
+// (also 0..1, errorThreshold/errorDenominator) and the source IP responsible for the
+// errors this window, tripping or recovering the breaker as needed.
+func (b *circuitBreaker) Observe(errorRate, threshold float64, offendingIP string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dangerousErrorRate.Set(errorRate)
+
+	switch {
+	case errorRate >= threshold:
+		b.trip(offendingIP)
+	case b.state == breakerHalfOpen && errorRate < threshold/2:
+		b.healthyStreak++
+		if b.healthyStreak >= circuitBreakerHealthyWindows {
+			b.reset()
+		}
+	default:
+		b.healthyStreak = 0
+	}
+
+	if b.state == breakerHalfOpen {
+		breakerStateGauge.Set(1)
+	} else {
+		breakerStateGauge.Set(0)
+	}
+}
+
+func (b *circuitBreaker) trip(offendingIP string) {
+	b.state = breakerHalfOpen
+	b.healthyStreak = 0
+	if b.intervalMs*2 <= b.maxIntervalMs {
+		b.intervalMs *= 2
+	} else {
+		b.intervalMs = b.maxIntervalMs
+	}
+	if offendingIP != "" {
+		b.coolingDown[offendingIP] = time.Now().Add(circuitBreakerCoolDown)
+	}
+}
+
+func (b *circuitBreaker) reset() {
+	b.state = breakerClosed
+	b.healthyStreak = 0
+	b.intervalMs = b.baseIntervalMs
+	b.coolingDown = map[string]time.Time{}
+}
+
+// IntervalMs returns the interval the scraper should currently wait between queries for a
+// single source IP.
+func (b *circuitBreaker) IntervalMs() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.intervalMs
+}
+
+// State returns the current breaker state, for the admin endpoint and Prometheus.
+func (b *circuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.String()
+}
+
+// BatchSize shrinks normalBatchSize while half-open, so a single tick queries fewer models.
+// A normalBatchSize of circuitBreakerBatchDivisor or less is returned unchanged.
+func (b *circuitBreaker) BatchSize(normalBatchSize int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != breakerHalfOpen || normalBatchSize <= circuitBreakerBatchDivisor {
+		return normalBatchSize
+	}
+	return normalBatchSize / circuitBreakerBatchDivisor
+}
+
+// RotatePool moves any source IP still cooling down from a previous trip to the back of
+// pool, preserving the relative order of the rest.
+func (b *circuitBreaker) RotatePool(pool []string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	ready := make([]string, 0, len(pool))
+	var cooling []string
+	for _, ip := range pool {
+		if until, found := b.coolingDown[ip]; found {
+			if now.Before(until) {
+				cooling = append(cooling, ip)
+				continue
+			}
+			delete(b.coolingDown, ip)
+		}
+		ready = append(ready, ip)
+	}
+	return append(ready, cooling...)
+}
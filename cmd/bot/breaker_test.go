@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+func TestCircuitBreakerTrip(t *testing.T) {
+	b := newCircuitBreaker(1000, 8000)
+
+	b.Observe(0.5, 0.2, "1.2.3.4")
+
+	if got := b.State(); got != "half-open" {
+		t.Errorf("State() = %q, want %q", got, "half-open")
+	}
+	if got := b.IntervalMs(); got != 2000 {
+		t.Errorf("IntervalMs() = %d, want %d", got, 2000)
+	}
+	if got := b.BatchSize(40); got != 10 {
+		t.Errorf("BatchSize(40) = %d, want %d", got, 10)
+	}
+}
+
+func TestCircuitBreakerIntervalCapsAtMax(t *testing.T) {
+	b := newCircuitBreaker(1000, 1500)
+
+	b.Observe(0.5, 0.2, "1.2.3.4")
+
+	if got := b.IntervalMs(); got != 1500 {
+		t.Errorf("IntervalMs() = %d, want %d (capped at max)", got, 1500)
+	}
+}
+
+func TestCircuitBreakerRampsBackToNormalAfterHealthyWindows(t *testing.T) {
+	b := newCircuitBreaker(1000, 8000)
+	b.Observe(0.5, 0.2, "1.2.3.4")
+
+	for i := 0; i < circuitBreakerHealthyWindows-1; i++ {
+		b.Observe(0.01, 0.2, "")
+		if got := b.State(); got != "half-open" {
+			t.Fatalf("after %d healthy windows, State() = %q, want %q", i+1, got, "half-open")
+		}
+	}
+
+	b.Observe(0.01, 0.2, "")
+
+	if got := b.State(); got != "closed" {
+		t.Errorf("State() = %q, want %q", got, "closed")
+	}
+	if got := b.IntervalMs(); got != 1000 {
+		t.Errorf("IntervalMs() = %d, want %d (reset to base)", got, 1000)
+	}
+	if got := b.BatchSize(40); got != 40 {
+		t.Errorf("BatchSize(40) = %d, want %d (no longer shrunk)", got, 40)
+	}
+}
+
+func TestCircuitBreakerHealthyStreakResetsOnMediumErrorRate(t *testing.T) {
+	b := newCircuitBreaker(1000, 8000)
+	b.Observe(0.5, 0.2, "1.2.3.4")
+	b.Observe(0.01, 0.2, "")
+	// Above threshold/2 but below threshold: neither healthy nor tripping, breaks the streak.
+	b.Observe(0.15, 0.2, "")
+	b.Observe(0.01, 0.2, "")
+
+	if got := b.State(); got != "half-open" {
+		t.Errorf("State() = %q, want %q (streak should have reset)", got, "half-open")
+	}
+}
+
+func TestCircuitBreakerRotatePool(t *testing.T) {
+	b := newCircuitBreaker(1000, 8000)
+	b.Observe(0.5, 0.2, "1.2.3.4")
+
+	got := b.RotatePool([]string{"1.2.3.4", "5.6.7.8", "9.9.9.9"})
+	want := []string{"5.6.7.8", "9.9.9.9", "1.2.3.4"}
+
+	if len(got) != len(want) {
+		t.Fatalf("RotatePool() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("RotatePool()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreakerRotatePoolUnaffectedWhenClosed(t *testing.T) {
+	b := newCircuitBreaker(1000, 8000)
+
+	pool := []string{"1.2.3.4", "5.6.7.8"}
+	got := b.RotatePool(pool)
+
+	for i := range pool {
+		if got[i] != pool[i] {
+			t.Errorf("RotatePool()[%d] = %q, want %q (order preserved when no IP is cooling down)", i, got[i], pool[i])
+		}
+	}
+}
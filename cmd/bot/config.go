@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -35,6 +36,26 @@ type coinPaymentsConfig struct {
 	subscriptionPacketModelNumber int
 }
 
+type btcPayConfig struct {
+	SubscriptionPacket string `json:"subscription_packet"` // subscription packet, format "10/15" meaning 10 models for 15 USD
+	ServerURL          string `json:"server_url"`          // BTCPay Server URL
+	StoreID            string `json:"store_id"`            // BTCPay Server store ID
+	APIKey             string `json:"api_key"`             // BTCPay Server API key
+	WebhookSecret      string `json:"webhook_secret"`      // secret used to verify the BTCPay-Sig webhook header
+	IPNListenAddress   string `json:"ipn_listen_address"`  // BTCPay webhook listen address
+
+	subscriptionPacketPrice       int
+	subscriptionPacketModelNumber int
+}
+
+type sourceIPConfig struct {
+	Address             string      `json:"address"`                 // the source IP address
+	Proxy               string      `json:"proxy"`                   // optional proxy to egress through, "socks5://" or "http(s)://"
+	Headers             [][2]string `json:"headers"`                 // optional HTTP headers specific to this source IP, in addition to the global ones
+	MaxIdleConnsPerHost int         `json:"max_idle_conns_per_host"` // optional override of the transport default
+	DisableHTTP2        bool        `json:"disable_http2"`           // force HTTP/1.1 on this source IP, to defeat fingerprinting
+}
+
 type config struct {
 	Website                     string              `json:"website"`                        // one of the following strings: "bongacams", "stripchat", "chaturbate"
 	PeriodSeconds               int                 `json:"period_seconds"`                 // the period of querying models statuses
@@ -47,7 +68,9 @@ type config struct {
 	BlockThreshold              int                 `json:"block_threshold"`                // do not send a message to the user after being blocked by him this number of times
 	Debug                       bool                `json:"debug"`                          // debug mode
 	IntervalMs                  int                 `json:"interval_ms"`                    // queries interval per IP address for rate limited access
+	MaxIntervalMs               int                 `json:"max_interval_ms"`                // ceiling IntervalMs may be backed off to by the circuit breaker, defaults to interval_ms if unset
 	SourceIPAddresses           []string            `json:"source_ip_addresses"`            // source IP addresses for rate limited access
+	SourceIPs                   []sourceIPConfig    `json:"source_ips"`                     // source IPs with per-IP proxy, headers and transport tuning, takes precedence over source_ip_addresses
 	DangerousErrorRate          string              `json:"dangerous_error_rate"`           // dangerous error rate, warn admin if it is reached, format "1000/10000"
 	EnableCookies               bool                `json:"enable_cookies"`                 // enable cookies, it can be useful to mitigate rate limits
 	Headers                     [][2]string         `json:"headers"`                        // HTTP headers to make queries with
@@ -55,9 +78,11 @@ type config struct {
 	ErrorReportingPeriodMinutes int                 `json:"error_reporting_period_minutes"` // the period of the error reports
 	Endpoints                   map[string]endpoint `json:"endpoints"`                      // the endpoints by simple name, used for the support of the bots in different languages accessing the same database
 	CoinPayments                *coinPaymentsConfig `json:"coin_payments"`                  // CoinPayments integration
+	BTCPay                      *btcPayConfig       `json:"btc_pay"`                        // BTCPay Server integration
 	HeavyUserRemainder          int                 `json:"heavy_user_remainder"`           // the maximum remainder of models to treat an user as heavy
 	MailHost                    string              `json:"mail_host"`                      // the hostname for email
 	MailListenAddress           string              `json:"mail_listen_address"`            // the address to listen to incoming mail
+	MetricsListenAddress        string              `json:"metrics_listen_address"`         // the address to serve Prometheus /metrics on, disabled if empty
 
 	errorThreshold   int
 	errorDenominator int
@@ -69,20 +94,41 @@ func readConfig(path string) *config {
 	file, err := os.Open(filepath.Clean(path))
 	checkErr(err)
 	defer func() { checkErr(file.Close()) }()
-	return parseConfig(file)
+	cfg := parseConfig(file)
+	liveConfig.Store(cfg)
+	return cfg
 }
 
 func parseConfig(r io.Reader) *config {
+	cfg, err := decodeConfig(r)
+	checkErr(err)
+	return cfg
+}
+
+// decodeConfig decodes and validates a config from r, without exiting the process on
+// error, so it can also be used by the SIGHUP reload path.
+func decodeConfig(r io.Reader) (*config, error) {
 	decoder := json.NewDecoder(r)
 	decoder.DisallowUnknownFields()
 	cfg := &config{}
-	err := decoder.Decode(cfg)
-	checkErr(err)
-	checkErr(checkConfig(cfg))
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, err
+	}
+	if err := checkConfig(cfg); err != nil {
+		return nil, err
+	}
 	if len(cfg.SourceIPAddresses) == 0 {
 		cfg.SourceIPAddresses = append(cfg.SourceIPAddresses, "")
 	}
-	return cfg
+	if len(cfg.SourceIPs) == 0 {
+		for _, address := range cfg.SourceIPAddresses {
+			cfg.SourceIPs = append(cfg.SourceIPs, sourceIPConfig{Address: address})
+		}
+	}
+	if cfg.MaxIntervalMs == 0 {
+		cfg.MaxIntervalMs = cfg.IntervalMs
+	}
+	return cfg, nil
 }
 
 func checkConfig(cfg *config) error {
@@ -91,6 +137,22 @@ func checkConfig(cfg *config) error {
 			return fmt.Errorf("cannot parse sourece IP address %s", x)
 		}
 	}
+	for _, x := range cfg.SourceIPs {
+		if net.ParseIP(x.Address) == nil {
+			return fmt.Errorf("cannot parse sourece IP address %s", x.Address)
+		}
+		if x.Proxy != "" {
+			proxyURL, err := url.Parse(x.Proxy)
+			if err != nil {
+				return fmt.Errorf("cannot parse proxy %s: %w", x.Proxy, err)
+			}
+			switch proxyURL.Scheme {
+			case "socks5", "http", "https":
+			default:
+				return fmt.Errorf("unsupported proxy scheme %s for source IP %s", proxyURL.Scheme, x.Address)
+			}
+		}
+	}
 	for _, x := range cfg.Endpoints {
 		if x.ListenAddress == "" {
 			return errors.New("configure listen_address")
@@ -141,6 +203,9 @@ func checkConfig(cfg *config) error {
 	if cfg.HeavyUserRemainder == 0 {
 		return errors.New("configure heavy_user_remainder")
 	}
+	if cfg.MaxIntervalMs != 0 && cfg.MaxIntervalMs < cfg.IntervalMs {
+		return errors.New("max_interval_ms cannot be lower than interval_ms")
+	}
 	if cfg.MailHost == "" {
 		return errors.New("configure mail_host")
 	}
@@ -175,6 +240,53 @@ func checkConfig(cfg *config) error {
 		}
 	}
 
+	if cfg.BTCPay != nil {
+		if err := checkBTCPayConfig(cfg.BTCPay); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkBTCPayConfig(cfg *btcPayConfig) error {
+	if cfg.ServerURL == "" {
+		return errors.New("configure server_url")
+	}
+	if cfg.StoreID == "" {
+		return errors.New("configure store_id")
+	}
+	if cfg.APIKey == "" {
+		return errors.New("configure api_key")
+	}
+	if cfg.WebhookSecret == "" {
+		return errors.New("configure webhook_secret")
+	}
+	if cfg.IPNListenAddress == "" {
+		return errors.New("configure ipn_listen_address")
+	}
+
+	if m := fractionRegexp.FindStringSubmatch(cfg.SubscriptionPacket); len(m) == 3 {
+		subscriptionPacketModelNumber, err := strconv.ParseInt(m[1], 10, 0)
+		if err != nil {
+			return err
+		}
+
+		subscriptionPacketPrice, err := strconv.ParseInt(m[2], 10, 0)
+		if err != nil {
+			return err
+		}
+
+		if subscriptionPacketModelNumber == 0 || subscriptionPacketPrice == 0 {
+			return errors.New("invalid subscription packet")
+		}
+
+		cfg.subscriptionPacketPrice = int(subscriptionPacketPrice)
+		cfg.subscriptionPacketModelNumber = int(subscriptionPacketModelNumber)
+	} else {
+		return errors.New("configure subscription_packet")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+)
+
+// liveConfig holds the currently active configuration, read by the scraper loop, the
+// Telegram endpoints and the mail and IPN listeners, and swapped atomically by
+// reloadConfig so none of them needs to restart to pick up a change.
+var liveConfig atomic.Pointer[config]
+
+// currentConfig returns the currently active configuration.
+func currentConfig() *config {
+	return liveConfig.Load()
+}
+
+// watchConfigReload installs a SIGHUP handler that reloads the config from path.
+func watchConfigReload(path string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			reloadConfig(path)
+		}
+	}()
+}
+
+// reloadConfig re-reads and re-validates the config at path and, if every change since
+// the running config is safe to apply live, swaps it in. Unsafe changes abort the reload,
+// logging the reason, and leave the running config untouched.
+func reloadConfig(path string) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		log.Printf("config reload: %v", err)
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("config reload: %v", err)
+		}
+	}()
+
+	newCfg, err := decodeConfig(file)
+	if err != nil {
+		log.Printf("config reload: %v", err)
+		return
+	}
+
+	if err := checkSafeConfigChange(currentConfig(), newCfg); err != nil {
+		log.Printf("config reload: refusing unsafe change: %v", err)
+		return
+	}
+
+	liveConfig.Store(newCfg)
+	log.Printf("config reloaded from %s", path)
+}
+
+// checkSafeConfigChange returns an error unless every difference between old and updated
+// is on the documented safe-to-reload list: PeriodSeconds, IntervalMs, SourceIPAddresses,
+// SourceIPs, Headers, MaxModels, NotFoundThreshold, BlockThreshold, DangerousErrorRate,
+// HeavyUserRemainder, per-endpoint translation strings, and AdminID. This is written as a
+// whitelist rather than a blacklist of known-unsafe fields so that a field added to config
+// later defaults to requiring a restart, instead of silently reloading live with no
+// consumer actually honoring the new value.
+func checkSafeConfigChange(old, updated *config) error {
+	for name, oldEndpoint := range old.Endpoints {
+		updatedEndpoint, found := updated.Endpoints[name]
+		if !found {
+			return fmt.Errorf("endpoint %s cannot be removed without a restart", name)
+		}
+		if oldEndpoint.ListenPath != updatedEndpoint.ListenPath || oldEndpoint.ListenAddress != updatedEndpoint.ListenAddress ||
+			oldEndpoint.WebhookDomain != updatedEndpoint.WebhookDomain || oldEndpoint.BotToken != updatedEndpoint.BotToken ||
+			oldEndpoint.CertificatePath != updatedEndpoint.CertificatePath || oldEndpoint.CertificateKeyPath != updatedEndpoint.CertificateKeyPath {
+			return fmt.Errorf("endpoint %s cannot be changed without a restart, except its translation", name)
+		}
+	}
+	for name := range updated.Endpoints {
+		if _, found := old.Endpoints[name]; !found {
+			return fmt.Errorf("endpoint %s cannot be added without a restart", name)
+		}
+	}
+
+	if (old.CoinPayments == nil) != (updated.CoinPayments == nil) {
+		return errors.New("coin_payments cannot be enabled or disabled without a restart")
+	}
+	if old.CoinPayments != nil && !reflect.DeepEqual(old.CoinPayments, updated.CoinPayments) {
+		return errors.New("coin_payments fields cannot be changed without a restart")
+	}
+	if (old.BTCPay == nil) != (updated.BTCPay == nil) {
+		return errors.New("btc_pay cannot be enabled or disabled without a restart")
+	}
+	if old.BTCPay != nil && !reflect.DeepEqual(old.BTCPay, updated.BTCPay) {
+		return errors.New("btc_pay fields cannot be changed without a restart")
+	}
+
+	// Neutralize the fields documented as safe to reload live by copying old's value onto a
+	// clone of updated, then compare what's left against old. Anything still different at
+	// that point is, by construction, not on the safe list above and aborts the reload.
+	safeUpdate := *updated
+	safeUpdate.PeriodSeconds = old.PeriodSeconds
+	safeUpdate.IntervalMs = old.IntervalMs
+	safeUpdate.SourceIPAddresses = old.SourceIPAddresses
+	safeUpdate.SourceIPs = old.SourceIPs
+	safeUpdate.Headers = old.Headers
+	safeUpdate.MaxModels = old.MaxModels
+	safeUpdate.NotFoundThreshold = old.NotFoundThreshold
+	safeUpdate.BlockThreshold = old.BlockThreshold
+	safeUpdate.DangerousErrorRate = old.DangerousErrorRate
+	safeUpdate.errorThreshold = old.errorThreshold
+	safeUpdate.errorDenominator = old.errorDenominator
+	safeUpdate.HeavyUserRemainder = old.HeavyUserRemainder
+	safeUpdate.AdminID = old.AdminID
+	safeUpdate.Endpoints = endpointsWithSafeTranslations(old.Endpoints, updated.Endpoints)
+	safeUpdate.CoinPayments = old.CoinPayments
+	safeUpdate.BTCPay = old.BTCPay
+
+	if !reflect.DeepEqual(&safeUpdate, old) {
+		return errors.New("config reload: a field outside the documented safe-to-reload set changed, restart the bot instead")
+	}
+	return nil
+}
+
+// endpointsWithSafeTranslations returns updated with each endpoint's Translation replaced
+// by the value from the matching entry in old, so a translation-only change does not trip
+// the catch-all unsafe-change check in checkSafeConfigChange.
+func endpointsWithSafeTranslations(old, updated map[string]endpoint) map[string]endpoint {
+	result := make(map[string]endpoint, len(updated))
+	for name, e := range updated {
+		if oldEndpoint, found := old[name]; found {
+			e.Translation = oldEndpoint.Translation
+		}
+		result[name] = e
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func baseTestConfig() *config {
+	return &config{
+		Website:            "stripchat",
+		PeriodSeconds:      60,
+		MaxModels:          10,
+		TimeoutSeconds:     30,
+		AdminID:            1,
+		AdminEndpoint:      "en",
+		DBPath:             "siren.db",
+		NotFoundThreshold:  3,
+		BlockThreshold:     3,
+		IntervalMs:         1000,
+		DangerousErrorRate: "1000/10000",
+		HeavyUserRemainder: 5,
+		MailHost:           "mail.example.com",
+		MailListenAddress:  ":25",
+		Endpoints: map[string]endpoint{
+			"en": {
+				ListenPath:      "/token",
+				ListenAddress:   ":8443",
+				CertificatePath: "cert.pem",
+				BotToken:        "token",
+				Translation:     "hello",
+			},
+		},
+	}
+}
+
+func TestCheckSafeConfigChangeAllowsSafeFields(t *testing.T) {
+	old := baseTestConfig()
+	updated := baseTestConfig()
+	updated.PeriodSeconds = 120
+	updated.IntervalMs = 2000
+	updated.SourceIPAddresses = []string{"1.2.3.4"}
+	updated.Headers = [][2]string{{"X-Test", "1"}}
+	updated.MaxModels = 20
+	updated.NotFoundThreshold = 5
+	updated.BlockThreshold = 5
+	updated.DangerousErrorRate = "1/10"
+	updated.HeavyUserRemainder = 9
+	updated.AdminID = 2
+	en := updated.Endpoints["en"]
+	en.Translation = "bonjour"
+	updated.Endpoints["en"] = en
+
+	if err := checkSafeConfigChange(old, updated); err != nil {
+		t.Errorf("checkSafeConfigChange() = %v, want nil for safe-field-only change", err)
+	}
+}
+
+func TestCheckSafeConfigChangeBlocksUnlistedFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config)
+	}{
+		{"db_path", func(c *config) { c.DBPath = "other.db" }},
+		{"mail_listen_address", func(c *config) { c.MailListenAddress = ":2525" }},
+		{"website", func(c *config) { c.Website = "bongacams" }},
+		{"timeout_seconds", func(c *config) { c.TimeoutSeconds = 60 }},
+		{"stat_password", func(c *config) { c.StatPassword = "changed" }},
+		{"endpoint bot_token", func(c *config) {
+			en := c.Endpoints["en"]
+			en.BotToken = "other-token"
+			c.Endpoints["en"] = en
+		}},
+		{"endpoint listen_address", func(c *config) {
+			en := c.Endpoints["en"]
+			en.ListenAddress = ":9443"
+			c.Endpoints["en"] = en
+		}},
+		{"endpoint removed", func(c *config) { delete(c.Endpoints, "en") }},
+		{"endpoint added", func(c *config) { c.Endpoints["fr"] = endpoint{} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := baseTestConfig()
+			updated := baseTestConfig()
+			tt.modify(updated)
+
+			if err := checkSafeConfigChange(old, updated); err == nil {
+				t.Errorf("checkSafeConfigChange() = nil, want error for %s change", tt.name)
+			}
+		})
+	}
+}
+
+func TestCheckSafeConfigChangeBlocksPaymentProviderFieldChanges(t *testing.T) {
+	old := baseTestConfig()
+	old.BTCPay = &btcPayConfig{WebhookSecret: "old-secret", IPNListenAddress: ":9000"}
+	updated := baseTestConfig()
+	updated.BTCPay = &btcPayConfig{WebhookSecret: "new-secret", IPNListenAddress: ":9000"}
+
+	if err := checkSafeConfigChange(old, updated); err == nil {
+		t.Error("checkSafeConfigChange() = nil, want error for btc_pay.webhook_secret change")
+	}
+}
+
+func TestCheckSafeConfigChangeBlocksPaymentProviderEnableDisable(t *testing.T) {
+	old := baseTestConfig()
+	updated := baseTestConfig()
+	updated.BTCPay = &btcPayConfig{WebhookSecret: "secret", IPNListenAddress: ":9000"}
+
+	if err := checkSafeConfigChange(old, updated); err == nil {
+		t.Error("checkSafeConfigChange() = nil, want error for enabling btc_pay live")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// dangerousErrorRate reports the current sliding-window error fraction against DangerousErrorRate.
+var dangerousErrorRate = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "siren_dangerous_error_rate",
+	Help: "Current fraction of failed requests in the sliding error window, 0..1.",
+})
+
+// breakerStateGauge reports the current circuit breaker state: 0 closed, 1 half-open.
+var breakerStateGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "siren_circuit_breaker_state",
+	Help: "Current circuit breaker state: 0 closed, 1 half-open.",
+})
+
+func init() {
+	prometheus.MustRegister(
+		dangerousErrorRate,
+		breakerStateGauge,
+	)
+}
+
+// startMetricsServer serves Prometheus metrics on cfg.MetricsListenAddress, if configured.
+// It is meant to be run on its own listener, kept off the public internet, separate from
+// the Telegram webhook, IPN and mail listeners.
+func startMetricsServer(cfg *config) {
+	if cfg.MetricsListenAddress == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		log.Printf("serving metrics on %s", cfg.MetricsListenAddress)
+		checkErr(http.ListenAndServe(cfg.MetricsListenAddress, mux))
+	}()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// noteMigrationSQL adds the free-text note column used to remember why a model was tracked
+// (schedule, tip menu, timezone, ...).
+const noteMigrationSQL = `ALTER TABLE tracking ADD COLUMN note TEXT NOT NULL DEFAULT ''`
+
+// migrateNotes adds the note column to the tracking table, if it is not already present.
+func migrateNotes(db *sql.DB) error {
+	if _, err := db.Exec(noteMigrationSQL); err != nil {
+		if strings.Contains(err.Error(), "duplicate column") {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// setNote records note against userID's tracking of model, replacing any existing note.
+func setNote(db *sql.DB, userID int64, model, note string) error {
+	result, err := db.Exec(`UPDATE tracking SET note = ? WHERE user_id = ? AND model = ?`, note, userID, model)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("user %d does not track %s", userID, model)
+	}
+	return nil
+}
+
+// clearNote removes any note against userID's tracking of model.
+func clearNote(db *sql.DB, userID int64, model string) error {
+	return setNote(db, userID, model, "")
+}
+
+// likeEscaper escapes the SQL LIKE wildcards "%" and "_", and the escape character itself,
+// so a user-supplied search substring is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// searchModels returns the models userID tracks whose name or note contains substring,
+// case-insensitively.
+func searchModels(db *sql.DB, userID int64, substring string) ([]string, error) {
+	like := "%" + likeEscaper.Replace(strings.ToLower(substring)) + "%"
+	rows, err := db.Query(
+		`SELECT model FROM tracking WHERE user_id = ? AND (LOWER(model) LIKE ? ESCAPE '\' OR LOWER(note) LIKE ? ESCAPE '\') ORDER BY model`,
+		userID, like, like,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { checkErr(rows.Close()) }()
+
+	var models []string
+	for rows.Next() {
+		var model string
+		if err := rows.Scan(&model); err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return models, rows.Err()
+}
+
+// mostNotedModel pairs a model with how many distinct users have attached a note to it.
+type mostNotedModel struct {
+	Model string
+	Count int
+}
+
+// mostNotedModels returns the limit models with the most non-empty notes across all users,
+// most-noted first, for the admin endpoint.
+func mostNotedModels(db *sql.DB, limit int) ([]mostNotedModel, error) {
+	rows, err := db.Query(
+		`SELECT model, COUNT(*) AS c FROM tracking WHERE note != '' GROUP BY model ORDER BY c DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { checkErr(rows.Close()) }()
+
+	var result []mostNotedModel
+	for rows.Next() {
+		var m mostNotedModel
+		if err := rows.Scan(&m.Model, &m.Count); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// parseNoteCommand splits the argument text of "/note <model> <text>" into the model name
+// and the note text.
+func parseNoteCommand(args string) (model, note string, err error) {
+	args = strings.TrimSpace(args)
+	parts := strings.SplitN(args, " ", 2)
+	if parts[0] == "" {
+		return "", "", fmt.Errorf("usage: /note <model> <text>")
+	}
+	model = parts[0]
+	if len(parts) > 1 {
+		note = strings.TrimSpace(parts[1])
+	}
+	return model, note, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+const trackingSchema = `CREATE TABLE tracking (user_id INTEGER NOT NULL, model TEXT NOT NULL, note TEXT NOT NULL DEFAULT '')`
+
+func newTestDB(t *testing.T, schema string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	return db
+}
+
+func TestSetNoteAndClearNote(t *testing.T) {
+	db := newTestDB(t, trackingSchema)
+	if _, err := db.Exec(`INSERT INTO tracking (user_id, model) VALUES (1, 'alice')`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	if err := setNote(db, 1, "alice", "likes cats"); err != nil {
+		t.Fatalf("setNote() = %v, want nil", err)
+	}
+
+	var note string
+	if err := db.QueryRow(`SELECT note FROM tracking WHERE user_id = 1 AND model = 'alice'`).Scan(&note); err != nil {
+		t.Fatalf("select note: %v", err)
+	}
+	if note != "likes cats" {
+		t.Errorf("note = %q, want %q", note, "likes cats")
+	}
+
+	if err := clearNote(db, 1, "alice"); err != nil {
+		t.Fatalf("clearNote() = %v, want nil", err)
+	}
+	if err := db.QueryRow(`SELECT note FROM tracking WHERE user_id = 1 AND model = 'alice'`).Scan(&note); err != nil {
+		t.Fatalf("select note after clear: %v", err)
+	}
+	if note != "" {
+		t.Errorf("note after clearNote = %q, want empty", note)
+	}
+}
+
+func TestSetNoteUntrackedModel(t *testing.T) {
+	db := newTestDB(t, trackingSchema)
+
+	if err := setNote(db, 1, "bob", "hello"); err == nil {
+		t.Error("setNote() = nil, want error when the user does not track model")
+	}
+}
+
+func TestSearchModels(t *testing.T) {
+	db := newTestDB(t, trackingSchema)
+	seed := []struct{ model, note string }{
+		{"Alice_Wonder", ""},
+		{"bob", "likes 50% off tips"},
+		{"dave", "likes 50x off tips"},
+		{"carol", "night_owl schedule"},
+		{"erin", "nightXowl schedule"},
+		{"frank", `back\slash note`},
+	}
+	for _, s := range seed {
+		if _, err := db.Exec(`INSERT INTO tracking (user_id, model, note) VALUES (1, ?, ?)`, s.model, s.note); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		substring string
+		want      []string
+	}{
+		{"case-insensitive model match", "wonder", []string{"Alice_Wonder"}},
+		{"literal percent is not a wildcard", "50%", []string{"bob"}},
+		{"literal underscore is not a wildcard", "night_owl", []string{"carol"}},
+		{"literal backslash", `back\slash`, []string{"frank"}},
+		{"no match", "nobody", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searchModels(db, 1, tt.substring)
+			if err != nil {
+				t.Fatalf("searchModels(%q) error = %v", tt.substring, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("searchModels(%q) = %v, want %v", tt.substring, got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("searchModels(%q)[%d] = %q, want %q", tt.substring, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMostNotedModels(t *testing.T) {
+	db := newTestDB(t, trackingSchema)
+	rows := []struct {
+		userID int64
+		model  string
+		note   string
+	}{
+		{1, "alice", "a"},
+		{2, "alice", "b"},
+		{1, "bob", "c"},
+		{3, "carol", ""},
+	}
+	for _, r := range rows {
+		if _, err := db.Exec(`INSERT INTO tracking (user_id, model, note) VALUES (?, ?, ?)`, r.userID, r.model, r.note); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	got, err := mostNotedModels(db, 2)
+	if err != nil {
+		t.Fatalf("mostNotedModels() error = %v", err)
+	}
+	want := []mostNotedModel{{"alice", 2}, {"bob", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("mostNotedModels() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("mostNotedModels()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/todokku/siren/btcpay"
+)
+
+// paymentProviderCoinPayments and paymentProviderBTCPay identify the payment backends a user
+// can pick from in the Telegram subscription menu when both are configured.
+const (
+	paymentProviderCoinPayments = "coinpayments"
+	paymentProviderBTCPay       = "btcpay"
+)
+
+// availablePaymentProviders returns the payment providers enabled in cfg, in the order they
+// should be offered in the Telegram menu.
+func availablePaymentProviders(cfg *config) []string {
+	var providers []string
+	if cfg.CoinPayments != nil {
+		providers = append(providers, paymentProviderCoinPayments)
+	}
+	if cfg.BTCPay != nil {
+		providers = append(providers, paymentProviderBTCPay)
+	}
+	return providers
+}
+
+// creditedInvoicesMigrationSQL creates the table used to make BTCPay invoice crediting
+// idempotent against at-least-once webhook delivery (retries and replays of a captured,
+// still-valid signature).
+const creditedInvoicesMigrationSQL = `CREATE TABLE IF NOT EXISTS credited_invoices (invoice_id TEXT PRIMARY KEY)`
+
+// migrateCreditedInvoices creates the credited_invoices table, if it is not already present.
+func migrateCreditedInvoices(db *sql.DB) error {
+	_, err := db.Exec(creditedInvoicesMigrationSQL)
+	return err
+}
+
+// creditSubscriptionPurchase credits modelNumber additional tracked models to userID for
+// invoiceID, the same way the CoinPayments IPN handler credits a settled payment. invoiceID
+// is recorded in credited_invoices in the same transaction as the credit, so a retried or
+// replayed webhook call for an invoice already credited is a no-op.
+func creditSubscriptionPurchase(db *sql.DB, invoiceID string, userID int64, modelNumber int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(`INSERT OR IGNORE INTO credited_invoices (invoice_id) VALUES (?)`, invoiceID)
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return tx.Commit()
+	}
+
+	result, err = tx.Exec(`UPDATE users SET max_models = max_models + ? WHERE id = ?`, modelNumber, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("user %d not found", userID)
+	}
+	return tx.Commit()
+}
+
+// startBTCPayListener serves the BTCPay Server webhook endpoint on cfg.IPNListenAddress,
+// crediting the purchased models to the user once an invoice settles.
+func startBTCPayListener(db *sql.DB, cfg *btcPayConfig) {
+	if cfg == nil {
+		return
+	}
+
+	handler := btcpay.WebhookHandler(cfg.WebhookSecret, func(invoiceID, orderID string) {
+		userID, err := strconv.ParseInt(orderID, 10, 64)
+		if err != nil {
+			log.Printf("btcpay: invoice %s settled with unparsable order id %q: %v", invoiceID, orderID, err)
+			return
+		}
+		if err := creditSubscriptionPurchase(db, invoiceID, userID, cfg.subscriptionPacketModelNumber); err != nil {
+			log.Printf("btcpay: invoice %s settled but failed to credit user %d: %v", invoiceID, userID, err)
+			return
+		}
+		log.Printf("btcpay: credited user %d with %d models for invoice %s", userID, cfg.subscriptionPacketModelNumber, invoiceID)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	go func() {
+		log.Printf("serving BTCPay IPN listener on %s", cfg.IPNListenAddress)
+		checkErr(http.ListenAndServe(cfg.IPNListenAddress, mux))
+	}()
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+const usersSchema = `CREATE TABLE users (id INTEGER PRIMARY KEY, max_models INTEGER NOT NULL DEFAULT 0)`
+
+func TestCreditSubscriptionPurchaseIsIdempotent(t *testing.T) {
+	db := newTestDB(t, usersSchema)
+	if err := migrateCreditedInvoices(db); err != nil {
+		t.Fatalf("migrateCreditedInvoices: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, max_models) VALUES (1, 5)`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	if err := creditSubscriptionPurchase(db, "invoice-1", 1, 10); err != nil {
+		t.Fatalf("creditSubscriptionPurchase() = %v, want nil", err)
+	}
+
+	var maxModels int
+	if err := db.QueryRow(`SELECT max_models FROM users WHERE id = 1`).Scan(&maxModels); err != nil {
+		t.Fatalf("select max_models: %v", err)
+	}
+	if maxModels != 15 {
+		t.Fatalf("max_models after first credit = %d, want %d", maxModels, 15)
+	}
+
+	// A retried or replayed webhook call for the same invoice must not credit again.
+	if err := creditSubscriptionPurchase(db, "invoice-1", 1, 10); err != nil {
+		t.Fatalf("creditSubscriptionPurchase() retry = %v, want nil", err)
+	}
+	if err := db.QueryRow(`SELECT max_models FROM users WHERE id = 1`).Scan(&maxModels); err != nil {
+		t.Fatalf("select max_models after retry: %v", err)
+	}
+	if maxModels != 15 {
+		t.Errorf("max_models after retried credit = %d, want %d (unchanged)", maxModels, 15)
+	}
+}
+
+func TestCreditSubscriptionPurchaseUnknownUser(t *testing.T) {
+	db := newTestDB(t, usersSchema)
+	if err := migrateCreditedInvoices(db); err != nil {
+		t.Fatalf("migrateCreditedInvoices: %v", err)
+	}
+
+	if err := creditSubscriptionPurchase(db, "invoice-2", 999, 10); err == nil {
+		t.Error("creditSubscriptionPurchase() = nil, want error for an unknown user")
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM credited_invoices WHERE invoice_id = 'invoice-2'`).Scan(&count); err != nil {
+		t.Fatalf("select credited_invoices: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("credited_invoices row for invoice-2 = %d, want 0 (rolled back)", count)
+	}
+}
@@ -1,11 +1,16 @@
 package lib
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"time"
+
+	"golang.org/x/net/proxy"
 )
 
 // Client wraps HTTP client and source IP address
@@ -16,34 +21,143 @@ type Client struct {
 	Addr net.Addr
 }
 
+// SourceIPOptions configures a single source IP slot: the proxy to egress through (if any),
+// extra headers to send with every request, and transport tuning to defeat fingerprinting.
+type SourceIPOptions struct {
+	// Address is the source IP address to bind to
+	Address string
+	// ProxyURL is an optional proxy to dial through, "socks5://" or "http(s)://"
+	ProxyURL string
+	// Headers are extra HTTP headers sent with every request made through this client
+	Headers [][2]string
+	// MaxIdleConnsPerHost overrides the transport default of 2 when non-zero
+	MaxIdleConnsPerHost int
+	// DisableHTTP2 forces the transport down to HTTP/1.1
+	DisableHTTP2 bool
+}
+
 // NoRedirect tells HTTP client to not to redirect
 func NoRedirect(_ *http.Request, _ []*http.Request) error { return http.ErrUseLastResponse }
 
 // HTTPClientWithTimeoutAndAddress returns HTTP client bound to specific IP address
 func HTTPClientWithTimeoutAndAddress(timeoutSeconds int, address string, cookies bool) *Client {
-	addr := &net.TCPAddr{IP: net.ParseIP(address)}
+	// SourceIPOptions.ProxyURL is empty here, so applyProxy is never invoked and this
+	// error is always nil.
+	client, err := HTTPClientWithOptions(timeoutSeconds, SourceIPOptions{Address: address}, cookies)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// HTTPClientWithOptions returns an HTTP client bound to opts.Address, optionally egressing
+// through opts.ProxyURL and sending opts.Headers with every request. It returns an error if
+// opts.ProxyURL is set but cannot be parsed or uses an unsupported scheme.
+func HTTPClientWithOptions(timeoutSeconds int, opts SourceIPOptions, cookies bool) (*Client, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(opts.Address)}
+	dialer := &net.Dialer{
+		LocalAddr: addr,
+		Timeout:   time.Second * time.Duration(timeoutSeconds),
+		KeepAlive: 30 * time.Second,
+		DualStack: true,
+	}
+	dialContext := func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			HTTPDialErrorsTotal.WithLabelValues(addr.String()).Inc()
+		}
+		return conn, err
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialContext,
+		ForceAttemptHTTP2:     !opts.DisableHTTP2,
+		MaxIdleConns:          10,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       http.DefaultTransport.(*http.Transport).IdleConnTimeout,
+		TLSHandshakeTimeout:   time.Second * time.Duration(timeoutSeconds),
+		ExpectContinueTimeout: time.Duration(0),
+		TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	if opts.DisableHTTP2 {
+		transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	}
+
+	if opts.ProxyURL != "" {
+		if err := applyProxy(transport, dialContext, opts.ProxyURL); err != nil {
+			return nil, fmt.Errorf("lib: invalid proxy for source IP %s: %w", opts.Address, err)
+		}
+	}
+
+	var rt http.RoundTripper = &instrumentedRoundTripper{sourceIP: addr.String(), next: transport}
+	if len(opts.Headers) > 0 {
+		rt = &headerRoundTripper{headers: opts.Headers, next: rt}
+	}
+
 	var client = &http.Client{
 		CheckRedirect: NoRedirect,
 		Timeout:       time.Second * time.Duration(timeoutSeconds),
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				LocalAddr: addr,
-				Timeout:   time.Second * time.Duration(timeoutSeconds),
-				KeepAlive: 30 * time.Second,
-				DualStack: true,
-			}).DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          10,
-			IdleConnTimeout:       http.DefaultTransport.(*http.Transport).IdleConnTimeout,
-			TLSHandshakeTimeout:   time.Second * time.Duration(timeoutSeconds),
-			ExpectContinueTimeout: time.Duration(0),
-			TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
-		},
+		Transport:     rt,
 	}
 	if cookies {
 		cookieJar, _ := cookiejar.New(nil)
 		client.Jar = cookieJar
 	}
-	return &Client{Client: client, Addr: addr}
+	return &Client{Client: client, Addr: addr}, nil
+}
+
+// applyProxy points transport at proxyURL, which is either a SOCKS5 URL ("socks5://host:port")
+// or an HTTP/HTTPS CONNECT proxy URL, dialing through dialContext in both cases so the
+// configured source IP is still honored.
+func applyProxy(transport *http.Transport, dialContext func(context.Context, string, string) (net.Conn, error), proxyURL string) error {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	switch parsed.Scheme {
+	case "socks5":
+		dialer, err := proxy.FromURL(parsed, contextlessDialer{dialContext})
+		if err != nil {
+			return err
+		}
+		transport.Proxy = nil
+		if ctxDialer, ok := dialer.(proxy.ContextDialer); ok {
+			transport.DialContext = ctxDialer.DialContext
+		} else {
+			transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.Dial(network, address)
+			}
+		}
+	case "http", "https":
+		transport.Proxy = http.ProxyURL(parsed)
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", parsed.Scheme)
+	}
+	return nil
+}
+
+// contextlessDialer adapts a DialContext func to the proxy.Dialer interface required by
+// golang.org/x/net/proxy, using context.Background() since that package predates contexts.
+type contextlessDialer struct {
+	dialContext func(context.Context, string, string) (net.Conn, error)
+}
+
+func (d contextlessDialer) Dial(network, address string) (net.Conn, error) {
+	return d.dialContext(context.Background(), network, address)
+}
+
+// headerRoundTripper sets extra headers on every outgoing request before delegating.
+type headerRoundTripper struct {
+	headers [][2]string
+	next    http.RoundTripper
+}
+
+func (t *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for _, header := range t.headers {
+		req.Header.Set(header[0], header[1])
+	}
+	return t.next.RoundTrip(req)
 }
@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func dummyDialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return nil, nil
+}
+
+func TestApplyProxyScheme(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxyURL string
+		wantErr  bool
+	}{
+		{"socks5", "socks5://127.0.0.1:1080", false},
+		{"http", "http://127.0.0.1:8080", false},
+		{"https", "https://127.0.0.1:8443", false},
+		{"unsupported scheme", "ftp://127.0.0.1:21", true},
+		{"unparsable url", "://bad", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &http.Transport{}
+			err := applyProxy(transport, dummyDialContext, tt.proxyURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyProxy(%q) error = %v, wantErr %v", tt.proxyURL, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			switch tt.name {
+			case "socks5":
+				if transport.Proxy != nil {
+					t.Errorf("applyProxy(%q): transport.Proxy set, want nil (dials through the SOCKS5 dialer)", tt.proxyURL)
+				}
+				if transport.DialContext == nil {
+					t.Errorf("applyProxy(%q): transport.DialContext not set", tt.proxyURL)
+				}
+			case "http", "https":
+				if transport.Proxy == nil {
+					t.Errorf("applyProxy(%q): transport.Proxy not set", tt.proxyURL)
+				}
+			}
+		})
+	}
+}
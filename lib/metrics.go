@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTPRequestsTotal counts outgoing HTTP requests made through Client, by source IP address.
+var HTTPRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "siren_http_requests_total",
+		Help: "Total number of outgoing HTTP requests, by source IP address.",
+	},
+	[]string{"source_ip"},
+)
+
+// HTTPRequestErrorsTotal counts outgoing HTTP requests that failed, by source IP address.
+var HTTPRequestErrorsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "siren_http_request_errors_total",
+		Help: "Total number of outgoing HTTP requests that failed, by source IP address.",
+	},
+	[]string{"source_ip"},
+)
+
+// HTTPRequestDurationSeconds observes outgoing HTTP request latency, by source IP address.
+var HTTPRequestDurationSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "siren_http_request_duration_seconds",
+		Help:    "Duration of outgoing HTTP requests, by source IP address.",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"source_ip"},
+)
+
+// HTTPDialErrorsTotal counts failed dial attempts to establish outgoing connections, by source IP address.
+var HTTPDialErrorsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "siren_http_dial_errors_total",
+		Help: "Total number of failed dial attempts, by source IP address.",
+	},
+	[]string{"source_ip"},
+)
+
+func init() {
+	prometheus.MustRegister(HTTPRequestsTotal, HTTPRequestErrorsTotal, HTTPRequestDurationSeconds, HTTPDialErrorsTotal)
+}
+
+// instrumentedRoundTripper wraps a http.RoundTripper to record per-source-IP Prometheus metrics.
+type instrumentedRoundTripper struct {
+	sourceIP string
+	next     http.RoundTripper
+}
+
+func (t *instrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	HTTPRequestDurationSeconds.WithLabelValues(t.sourceIP).Observe(time.Since(start).Seconds())
+	HTTPRequestsTotal.WithLabelValues(t.sourceIP).Inc()
+	if err != nil {
+		HTTPRequestErrorsTotal.WithLabelValues(t.sourceIP).Inc()
+	}
+	return resp, err
+}